@@ -134,6 +134,24 @@ int dqlite_node_set_connect_func(dqlite_node *n,
 int dqlite_node_set_network_latency(dqlite_node *n,
 				    unsigned long long nanoseconds);
 
+/**
+ * Set the raft heartbeat and election timeouts, expressed in milliseconds.
+ *
+ * This is an alternative to dqlite_node_set_network_latency() for callers that
+ * want to control these values directly, for example when running over links
+ * with high or irregular latency. The leader sends a heartbeat to followers
+ * every `heartbeat_timeout` milliseconds, and a follower that hasn't heard from
+ * the leader for `election_timeout` milliseconds starts a new election.
+ *
+ * The heartbeat timeout must be non-zero and lower than the election timeout.
+ * The defaults are 500 and 3000 milliseconds respectively.
+ *
+ * This function must be called before calling dqlite_node_start().
+ */
+int dqlite_node_set_raft_timeouts(dqlite_node *n,
+				  unsigned heartbeat_timeout,
+				  unsigned election_timeout);
+
 /**
  * Set the failure domain associated with this node.
  *
@@ -162,6 +180,21 @@ int dqlite_node_set_failure_domain(dqlite_node *n, unsigned long long code);
 int dqlite_node_set_snapshot_params(dqlite_node *n, unsigned snapshot_threshold,
                                     unsigned snapshot_trailing);
 
+/**
+ * Set the WAL checkpoint threshold for this node, expressed in number of WAL
+ * frames.
+ *
+ * After a write transaction is committed, the leader checks the size of the
+ * database WAL and, if it contains at least this many frames and there are no
+ * readers, triggers a checkpoint that gets replicated to all nodes. Lowering
+ * this number keeps the WAL smaller at the cost of more frequent checkpoints.
+ *
+ * The threshold must be non-zero. The default is 1000 frames.
+ *
+ * This function must be called before calling dqlite_node_start().
+ */
+int dqlite_node_set_checkpoint_threshold(dqlite_node *n, unsigned threshold);
+
 /**
  * Start a dqlite node.
  *
@@ -1,10 +1,14 @@
+#include <limits.h>
+
 #include <raft.h>
 
 #include "lib/assert.h"
+#include "lib/byte.h"
 #include "lib/serialize.h"
 
 #include "command.h"
 #include "fsm.h"
+#include "logger.h"
 #include "vfs.h"
 
 struct fsm
@@ -259,6 +263,17 @@ SERIALIZE__IMPLEMENT(snapshotHeader, SNAPSHOT_HEADER);
 SERIALIZE__DEFINE(snapshotDatabase, SNAPSHOT_DATABASE);
 SERIALIZE__IMPLEMENT(snapshotDatabase, SNAPSHOT_DATABASE);
 
+/* Trailer appended after the last database, holding the number of databases.
+ * It's followed by one checksum per database, in the same order as the
+ * databases themselves: each checksum is the CRC-32 of the encoded database
+ * header (filename, main_size and wal_size) followed by the database content,
+ * stored widened into a uint64 slot. Older versions stop reading after the last
+ * database, so adding the trailer doesn't require bumping the snapshot
+ * format. */
+#define SNAPSHOT_CHECKSUMS(X, ...) X(uint64, n, ##__VA_ARGS__)
+SERIALIZE__DEFINE(snapshotChecksums, SNAPSHOT_CHECKSUMS);
+SERIALIZE__IMPLEMENT(snapshotChecksums, SNAPSHOT_CHECKSUMS);
+
 /* Encode the global snapshot header. */
 static int encodeSnapshotHeader(unsigned n, struct raft_buffer *buf)
 {
@@ -323,6 +338,123 @@ err:
 	return rv;
 }
 
+/* Encode the checksums trailer of the given snapshot buffers, which must
+ * contain the global header followed by @n database header/content pairs. Each
+ * checksum covers both the header and the content of a database, so that a
+ * corrupted filename or size is detected as well. */
+static int encodeChecksums(struct raft_buffer *bufs,
+			   unsigned n,
+			   struct raft_buffer *buf)
+{
+	struct snapshotChecksums trailer;
+	void *cursor;
+	unsigned i;
+	trailer.n = n;
+	buf->len = snapshotChecksums__sizeof(&trailer);
+	buf->len += n * sizeof(uint64_t);
+	buf->base = raft_malloc(buf->len);
+	if (buf->base == NULL) {
+		return RAFT_NOMEM;
+	}
+	cursor = buf->base;
+	snapshotChecksums__encode(&trailer, &cursor);
+	for (i = 0; i < n; i++) {
+		struct raft_buffer *header = &bufs[1 + i * 2];
+		struct raft_buffer *content = &bufs[2 + i * 2];
+		uint64_t crc;
+		crc = byte__crc32(content->base, content->len,
+				  byte__crc32(header->base, header->len, 0));
+		uint64__encode(&crc, &cursor);
+	}
+	return 0;
+}
+
+/* Skip over the header and content of the next database in a snapshot. */
+static int skipDatabase(struct cursor *cursor, struct snapshotDatabase *header)
+{
+	size_t n;
+	int rv;
+	rv = snapshotDatabase__decode(cursor, header);
+	if (rv != 0) {
+		return rv;
+	}
+	/* Check each size separately, since their sum could overflow. */
+	if (header->main_size > cursor->cap) {
+		return RAFT_MALFORMED;
+	}
+	if (header->wal_size > cursor->cap - header->main_size) {
+		return RAFT_MALFORMED;
+	}
+	n = header->main_size + header->wal_size;
+	cursor->p += n;
+	cursor->cap -= n;
+	return 0;
+}
+
+/* Verify the checksums of the @n databases contained in a snapshot, starting at
+ * the given cursor position. Snapshots created by older versions have no
+ * checksums trailer and are accepted as they are. */
+static int verifyChecksums(struct fsm *f, struct cursor cursor, unsigned n)
+{
+	struct cursor trailer = cursor;
+	struct snapshotChecksums checksums;
+	struct snapshotDatabase header;
+	unsigned i;
+	int rv;
+
+	for (i = 0; i < n; i++) {
+		rv = skipDatabase(&trailer, &header);
+		if (rv != 0) {
+			return rv;
+		}
+	}
+
+	if (trailer.cap == 0) {
+		loggerEmit(f->logger, DQLITE_WARN,
+			   "snapshot has no checksums, skip verification");
+		return 0;
+	}
+
+	rv = snapshotChecksums__decode(&trailer, &checksums);
+	if (rv != 0) {
+		return rv;
+	}
+	if (checksums.n != n) {
+		return RAFT_MALFORMED;
+	}
+
+	for (i = 0; i < n; i++) {
+		const uint8_t *start = cursor.p;
+		uint64_t expected;
+		uint64_t actual;
+		uint32_t crc;
+		size_t size;
+		rv = uint64__decode(&trailer, &expected);
+		if (rv != 0) {
+			return rv;
+		}
+		rv = snapshotDatabase__decode(&cursor, &header);
+		assert(rv == 0); /* Already checked above */
+		size = (size_t)((const uint8_t *)cursor.p - start);
+		crc = byte__crc32(start, size, 0);
+		size = header.main_size + header.wal_size;
+		actual = byte__crc32(cursor.p, size, crc);
+		cursor.p += size;
+		cursor.cap -= size;
+		if (actual != expected) {
+			loggerEmit(f->logger, DQLITE_LOG_ERROR,
+				   "snapshot database %s: checksum mismatch: "
+				   "expected %08llx, got %08llx",
+				   header.filename,
+				   (unsigned long long)expected,
+				   (unsigned long long)actual);
+			return RAFT_CORRUPT;
+		}
+	}
+
+	return 0;
+}
+
 /* Decode the database contained in a snapshot. */
 static int decodeDatabase(struct fsm *f, struct cursor *cursor)
 {
@@ -364,6 +496,7 @@ static int decodeDatabase(struct fsm *f, struct cursor *cursor)
 		return rv;
 	}
 	cursor->p += n;
+	cursor->cap -= n;
 
 	return 0;
 }
@@ -392,6 +525,7 @@ static int fsm__snapshot(struct raft_fsm *fsm,
 
 	*n_bufs = 1;      /* Snapshot header */
 	*n_bufs += n * 2; /* Database header an content */
+	*n_bufs += 1;     /* Checksums trailer */
 	*bufs = raft_malloc(*n_bufs * sizeof **bufs);
 	if (*bufs == NULL) {
 		rv = RAFT_NOMEM;
@@ -415,8 +549,18 @@ static int fsm__snapshot(struct raft_fsm *fsm,
 		i += 2;
 	}
 
+	rv = encodeChecksums(*bufs, n, &(*bufs)[i]);
+	if (rv != 0) {
+		goto err_after_encode_databases;
+	}
+
 	return 0;
 
+err_after_encode_databases:
+	for (i = 0; i < *n_bufs - 1; i++) {
+		raft_free((*bufs)[i].base);
+	}
+	goto err_after_bufs_alloc;
 err_after_encode_header:
 	do {
 		raft_free((*bufs)[i].base);
@@ -445,6 +589,15 @@ static int fsm__restore(struct raft_fsm *fsm, struct raft_buffer *buf)
 		return RAFT_MALFORMED;
 	}
 
+	if (header.n > UINT_MAX) {
+		return RAFT_MALFORMED;
+	}
+
+	rv = verifyChecksums(f, cursor, (unsigned)header.n);
+	if (rv != 0) {
+		return rv;
+	}
+
 	for (i = 0; i < header.n; i++) {
 		rv = decodeDatabase(f, &cursor);
 		if (rv != 0) {
@@ -95,4 +95,10 @@ DQLITE_INLINE size_t byte__pad64(size_t size)
 	return size;
 }
 
+/**
+ * Compute the CRC-32 checksum of the given buffer, extending the given initial
+ * @init value (pass 0 when starting a new checksum).
+ */
+uint32_t byte__crc32(const void *buf, size_t size, uint32_t init);
+
 #endif /* LIB_BYTE_H_ */
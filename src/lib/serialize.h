@@ -293,8 +293,12 @@ DQLITE_INLINE int text__decode(struct cursor *cursor, text_t *value)
 	if (len == cursor->cap) {
 		return DQLITE_PARSE;
 	}
+	n = byte__pad64(len + 1);
+	/* The padding of the string must fit in the buffer too. */
+	if (n > cursor->cap) {
+		return DQLITE_PARSE;
+	}
 	*value = cursor->p;
-	n = byte__pad64(strlen(*value) + 1);
 	cursor->p += n;
 	cursor->cap -= n;
 	return 0;
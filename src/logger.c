@@ -1,3 +1,4 @@
+#include <stdarg.h>
 #include <stdio.h>
 #include <string.h>
 
@@ -40,3 +41,11 @@ void loggerDefaultEmit(void *data, int level, const char *fmt, va_list args)
 
 	fprintf(stderr, "%s\n", buf);
 }
+
+void loggerEmit(struct logger *l, int level, const char *fmt, ...)
+{
+	va_list args;
+	va_start(args, fmt);
+	l->emit(l->data, level, fmt, args);
+	va_end(args);
+}
@@ -23,6 +23,9 @@ struct logger
 /* Default implementation of dqlite_emit, using stderr. */
 void loggerDefaultEmit(void *data, int level, const char *fmt, va_list args);
 
+/* Emit a log message with a certain level using the given logger. */
+void loggerEmit(struct logger *l, int level, const char *fmt, ...);
+
 /* Emit a log message with a certain level. */
 /* #define debugf(L, FORMAT, ...) \ */
 /* 	logger__emit(L, DQLITE_DEBUG, FORMAT, ##__VA_ARGS__) */
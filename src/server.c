@@ -61,7 +61,8 @@ int dqlite__init(struct dqlite_node *d,
 			 raft_errmsg(&d->raft));
 		return rv;
 	}
-	/* TODO: expose these values through some API */
+	/* Default values, which can be changed with the dqlite_node_set_*()
+	 * functions before starting the node. */
 	raft_set_election_timeout(&d->raft, 3000);
 	raft_set_heartbeat_timeout(&d->raft, 500);
 	raft_set_snapshot_threshold(&d->raft, 1024);
@@ -333,6 +334,26 @@ int dqlite_node_set_network_latency(dqlite_node *t,
 	return 0;
 }
 
+int dqlite_node_set_raft_timeouts(dqlite_node *n,
+				  unsigned heartbeat_timeout,
+				  unsigned election_timeout)
+{
+	if (n->running) {
+		return DQLITE_MISUSE;
+	}
+	if (heartbeat_timeout == 0) {
+		return DQLITE_MISUSE;
+	}
+	/* Followers must have a chance to receive a heartbeat from the leader
+	 * before their election timeout expires. */
+	if (election_timeout <= heartbeat_timeout) {
+		return DQLITE_MISUSE;
+	}
+	raft_set_heartbeat_timeout(&n->raft, heartbeat_timeout);
+	raft_set_election_timeout(&n->raft, election_timeout);
+	return 0;
+}
+
 int dqlite_node_set_failure_domain(dqlite_node *n, unsigned long long code)
 {
 	n->config.failure_domain = code;
@@ -362,6 +383,18 @@ int dqlite_node_set_snapshot_params(dqlite_node *n, unsigned snapshot_threshold,
     return 0;
 }
 
+int dqlite_node_set_checkpoint_threshold(dqlite_node *n, unsigned threshold)
+{
+	if (n->running) {
+		return DQLITE_MISUSE;
+	}
+	if (threshold == 0) {
+		return DQLITE_MISUSE;
+	}
+	n->config.checkpoint_threshold = threshold;
+	return 0;
+}
+
 static int maybeBootstrap(dqlite_node *d,
 			  dqlite_node_id id,
 			  const char *address)
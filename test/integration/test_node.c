@@ -4,6 +4,7 @@
 #include "../lib/sqlite.h"
 
 #include "../../include/dqlite.h"
+#include "../../src/server.h"
 
 /******************************************************************************
  *
@@ -133,3 +134,118 @@ TEST(node, snapshotParamsThresholdLargerThanTrailing, setUp, tearDown, 0, NULL)
 
         return MUNIT_OK;
 }
+
+TEST(node, raftTimeouts, setUp, tearDown, 0, NULL)
+{
+        struct fixture *f = data;
+        int rv;
+
+        munit_assert_uint(f->node->raft.heartbeat_timeout, ==, 500);
+        munit_assert_uint(f->node->raft.election_timeout, ==, 3000);
+
+        rv = dqlite_node_set_raft_timeouts(f->node, 1000, 10000);
+        munit_assert_int(rv, ==, 0);
+
+        munit_assert_uint(f->node->raft.heartbeat_timeout, ==, 1000);
+        munit_assert_uint(f->node->raft.election_timeout, ==, 10000);
+
+        rv = dqlite_node_start(f->node);
+        munit_assert_int(rv, ==, 0);
+        rv = dqlite_node_stop(f->node);
+        munit_assert_int(rv, ==, 0);
+
+        return MUNIT_OK;
+}
+
+TEST(node, raftTimeoutsRunning, setUp, tearDown, 0, NULL)
+{
+        struct fixture *f = data;
+        int rv;
+
+        rv = dqlite_node_start(f->node);
+        munit_assert_int(rv, ==, 0);
+
+        rv = dqlite_node_set_raft_timeouts(f->node, 1000, 10000);
+        munit_assert_int(rv, ==, DQLITE_MISUSE);
+
+        rv = dqlite_node_stop(f->node);
+        munit_assert_int(rv, ==, 0);
+
+        return MUNIT_OK;
+}
+
+TEST(node, raftTimeoutsZeroHeartbeat, setUp, tearDown, 0, NULL)
+{
+        struct fixture *f = data;
+        int rv;
+
+        rv = dqlite_node_set_raft_timeouts(f->node, 0, 10000);
+        munit_assert_int(rv, ==, DQLITE_MISUSE);
+        munit_assert_uint(f->node->raft.election_timeout, ==, 3000);
+
+        return MUNIT_OK;
+}
+
+TEST(node, raftTimeoutsHeartbeatNotLowerThanElection, setUp, tearDown, 0, NULL)
+{
+        struct fixture *f = data;
+        int rv;
+
+        rv = dqlite_node_set_raft_timeouts(f->node, 1000, 1000);
+        munit_assert_int(rv, ==, DQLITE_MISUSE);
+        munit_assert_uint(f->node->raft.heartbeat_timeout, ==, 500);
+
+        return MUNIT_OK;
+}
+
+TEST(node, checkpointThreshold, setUp, tearDown, 0, NULL)
+{
+        struct fixture *f = data;
+        int rv;
+
+        munit_assert_uint(f->node->config.checkpoint_threshold, ==, 1000);
+
+        rv = dqlite_node_set_checkpoint_threshold(f->node, 100);
+        munit_assert_int(rv, ==, 0);
+
+        /* This is the threshold that the leader checks after each commit,
+         * see the replication checkpoint tests. */
+        munit_assert_uint(f->node->config.checkpoint_threshold, ==, 100);
+
+        rv = dqlite_node_start(f->node);
+        munit_assert_int(rv, ==, 0);
+        rv = dqlite_node_stop(f->node);
+        munit_assert_int(rv, ==, 0);
+
+        return MUNIT_OK;
+}
+
+TEST(node, checkpointThresholdRunning, setUp, tearDown, 0, NULL)
+{
+        struct fixture *f = data;
+        int rv;
+
+        rv = dqlite_node_start(f->node);
+        munit_assert_int(rv, ==, 0);
+
+        rv = dqlite_node_set_checkpoint_threshold(f->node, 100);
+        munit_assert_int(rv, ==, DQLITE_MISUSE);
+        munit_assert_uint(f->node->config.checkpoint_threshold, ==, 1000);
+
+        rv = dqlite_node_stop(f->node);
+        munit_assert_int(rv, ==, 0);
+
+        return MUNIT_OK;
+}
+
+TEST(node, checkpointThresholdZero, setUp, tearDown, 0, NULL)
+{
+        struct fixture *f = data;
+        int rv;
+
+        rv = dqlite_node_set_checkpoint_threshold(f->node, 0);
+        munit_assert_int(rv, ==, DQLITE_MISUSE);
+        munit_assert_uint(f->node->config.checkpoint_threshold, ==, 1000);
+
+        return MUNIT_OK;
+}
@@ -80,6 +80,7 @@ struct server
                                                                        \
 		_rc = config__init(&_s->config, I + 1, address);       \
 		munit_assert_int(_rc, ==, 0);                          \
+		_s->config.logger = _s->logger;                        \
                                                                        \
 		registry__init(&_s->registry, &_s->config);            \
                                                                        \
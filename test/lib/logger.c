@@ -11,10 +11,17 @@ void test_logger_emit(void *data, int level, const char *format, va_list args)
 	struct test_logger *t = data;
 	char buf[1024];
 	const char *level_name;
+	va_list copy;
 	int i;
 
 	(void)data;
 
+	/* Remember the last message, so tests can make assertions on it. */
+	t->level = level;
+	va_copy(copy, args);
+	vsnprintf(t->message, sizeof t->message, format, copy);
+	va_end(copy);
+
 	switch (level) {
 		case DQLITE_DEBUG:
 			level_name = "DEBUG";
@@ -55,6 +62,8 @@ void test_logger_setup(const MunitParameter params[], struct logger *l)
 
 	t = munit_malloc(sizeof *t);
 	t->data = NULL;
+	t->level = -1;
+	t->message[0] = 0;
 
 	l->data = t;
 	l->emit = test_logger_emit;
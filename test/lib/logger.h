@@ -16,6 +16,8 @@ struct test_logger
 {
 	unsigned id;
 	void *data;
+	int level;          /* Level of the last emitted message. */
+	char message[1024]; /* Text of the last emitted message. */
 };
 
 void test_logger_emit(void *data, int level, const char *fmt, va_list args);
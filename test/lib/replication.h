@@ -0,0 +1,82 @@
+/**
+ * Helpers to run SQL statements through struct leader objects of a test
+ * cluster.
+ *
+ * The including test fixture must have a leaders[N_SERVERS] array, a stmt
+ * pointer and a req exec request, and the test file must define a
+ * fixture_exec_cb() exec callback.
+ */
+
+#ifndef TEST_REPLICATION_H
+#define TEST_REPLICATION_H
+
+#include "../../src/leader.h"
+#include "../../src/registry.h"
+
+#include "cluster.h"
+
+#define SETUP_LEADER(I)                                           \
+	do {                                                      \
+		struct leader *leader = &f->leaders[I];           \
+		struct registry *registry = CLUSTER_REGISTRY(I);  \
+		struct db *db;                                    \
+		int rc2;                                          \
+		rc2 = registry__db_get(registry, "test.db", &db); \
+		munit_assert_int(rc2, ==, 0);                     \
+		rc2 = leader__init(leader, db, CLUSTER_RAFT(I));  \
+		munit_assert_int(rc2, ==, 0);                     \
+	} while (0)
+
+#define TEAR_DOWN_LEADER(I)                             \
+	do {                                            \
+		struct leader *leader = &f->leaders[I]; \
+		leader__close(leader);                  \
+	} while (0)
+
+/* Return the i'th leader object. */
+#define LEADER(I) &f->leaders[I]
+
+/* Return the SQLite connection of the i'th leader object */
+#define CONN(I) (LEADER(I))->conn
+
+/* Prepare the fixture's statement using the connection of the I'th leader */
+#define PREPARE(I, SQL)                                                     \
+	{                                                                   \
+		int rc2;                                                    \
+		rc2 = sqlite3_prepare_v2(CONN(I), SQL, -1, &f->stmt, NULL); \
+		munit_assert_int(rc2, ==, 0);                               \
+	}
+
+/* Reset the fixture's statement, expecting the given return code. */
+#define RESET(RC)                              \
+	{                                      \
+		int rc2;                       \
+		rc2 = sqlite3_reset(f->stmt);  \
+		munit_assert_int(rc2, ==, RC); \
+	}
+
+/* Finalize the fixture's statement */
+#define FINALIZE                                 \
+	{                                        \
+		int rc2;                         \
+		rc2 = sqlite3_finalize(f->stmt); \
+		munit_assert_int(rc2, ==, 0);    \
+	}
+
+/* Submit an exec request using the I'th leader. */
+#define EXEC(I)                                                 \
+	{                                                       \
+		int rc2;                                        \
+		rc2 = leader__exec(LEADER(I), &f->req, f->stmt, \
+				   fixture_exec_cb);            \
+		munit_assert_int(rc2, ==, 0);                   \
+	}
+
+/* Convenience to prepare, execute and finalize a statement. */
+#define EXEC_SQL(I, SQL)                        \
+	PREPARE(I, SQL);                        \
+	EXEC(I);                                \
+	CLUSTER_APPLIED(CLUSTER_LAST_INDEX(I)); \
+	FINALIZE
+
+#endif /* TEST_REPLICATION_H */
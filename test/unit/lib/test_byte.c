@@ -0,0 +1,47 @@
+#include "../../../src/lib/byte.h"
+
+#include "../../lib/runner.h"
+
+TEST_MODULE(lib_byte);
+
+/******************************************************************************
+ *
+ * byte__crc32
+ *
+ ******************************************************************************/
+
+TEST_SUITE(crc32);
+
+/* The checksum of the standard check input matches the reference value. */
+TEST_CASE(crc32, check, NULL)
+{
+	const char *buf = "123456789";
+	(void)data;
+	(void)params;
+	munit_assert_uint32(byte__crc32(buf, strlen(buf), 0), ==, 0xcbf43926);
+	return MUNIT_OK;
+}
+
+/* The checksum of an empty buffer is the initial value. */
+TEST_CASE(crc32, empty, NULL)
+{
+	(void)data;
+	(void)params;
+	munit_assert_uint32(byte__crc32(NULL, 0, 0), ==, 0);
+	munit_assert_uint32(byte__crc32(NULL, 0, 123), ==, 123);
+	return MUNIT_OK;
+}
+
+/* A checksum can be computed incrementally by passing the previous result as
+ * initial value. */
+TEST_CASE(crc32, incremental, NULL)
+{
+	const char *buf = "123456789";
+	uint32_t crc;
+	(void)data;
+	(void)params;
+	crc = byte__crc32(buf, 4, 0);
+	crc = byte__crc32(buf + 4, strlen(buf) - 4, crc);
+	munit_assert_uint32(crc, ==, 0xcbf43926);
+	return MUNIT_OK;
+}
@@ -344,6 +344,22 @@ TEST_CASE(decode, short, NULL)
 	return MUNIT_OK;
 }
 
+/* The given buffer ends after a string's null byte, but before its padding. */
+TEST_CASE(decode, short_padding, NULL)
+{
+	struct fixture *f = data;
+	void *buf = munit_malloc(8);
+	struct cursor cursor = {buf, 5};
+	int rc;
+	(void)params;
+	strcpy(buf, "John");
+	rc = person__decode(&cursor, &f->person);
+	munit_assert_int(rc, ==, DQLITE_PARSE);
+	munit_assert_int(cursor.cap, ==, 5);
+	free(buf);
+	return MUNIT_OK;
+}
+
 /* Decode a custom complex field. */
 TEST_CASE(decode, custom, NULL)
 {
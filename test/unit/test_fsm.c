@@ -0,0 +1,251 @@
+#include <limits.h>
+
+#include "../lib/cluster.h"
+#include "../lib/replication.h"
+#include "../lib/runner.h"
+
+#include "../../src/lib/byte.h"
+#include "../../src/lib/serialize.h"
+
+/******************************************************************************
+ *
+ * Fixture
+ *
+ ******************************************************************************/
+
+struct fixture
+{
+	FIXTURE_CLUSTER;
+	struct leader leaders[N_SERVERS];
+	sqlite3_stmt *stmt;
+	struct exec req;
+	bool invoked;
+	int status;
+};
+
+static void fixture_exec_cb(struct exec *req, int status)
+{
+	struct fixture *f = req->data;
+	f->invoked = true;
+	f->status = status;
+}
+
+static void *setUp(const MunitParameter params[], void *user_data)
+{
+	struct fixture *f = munit_malloc(sizeof *f);
+	SETUP_CLUSTER(V2);
+	SETUP_LEADER(0);
+	f->req.data = f;
+	return f;
+}
+
+static void tearDown(void *data)
+{
+	struct fixture *f = data;
+	TEAR_DOWN_LEADER(0);
+	TEAR_DOWN_CLUSTER;
+	free(f);
+}
+
+/******************************************************************************
+ *
+ * Helper macros.
+ *
+ ******************************************************************************/
+
+/* Take a snapshot of the I'th FSM and flatten it into BUF, including only the
+ * first N buffers, or all of them if N is 0. */
+#define SNAPSHOT(I, BUF, N)                                     \
+	{                                                       \
+		struct raft_fsm *fsm_ = &f->fsms[I];            \
+		struct raft_buffer *bufs_;                      \
+		unsigned n_bufs_;                               \
+		unsigned n_;                                    \
+		unsigned i_;                                    \
+		uint8_t *cursor_;                               \
+		int rv_;                                        \
+		rv_ = fsm_->snapshot(fsm_, &bufs_, &n_bufs_);   \
+		munit_assert_int(rv_, ==, 0);                   \
+		n_ = N == 0 ? n_bufs_ : N;                      \
+		(BUF)->len = 0;                                 \
+		for (i_ = 0; i_ < n_; i_++) {                   \
+			(BUF)->len += bufs_[i_].len;            \
+		}                                               \
+		(BUF)->base = raft_malloc((BUF)->len);          \
+		munit_assert_ptr_not_null((BUF)->base);         \
+		cursor_ = (BUF)->base;                          \
+		for (i_ = 0; i_ < n_bufs_; i_++) {              \
+			if (i_ < n_) {                          \
+				memcpy(cursor_, bufs_[i_].base, \
+				       bufs_[i_].len);          \
+				cursor_ += bufs_[i_].len;       \
+			}                                       \
+			raft_free(bufs_[i_].base);              \
+		}                                               \
+		raft_free(bufs_);                               \
+	}
+
+/* Restore the given snapshot buffer into the I'th FSM, expecting RV. */
+#define RESTORE(I, BUF, RV)                          \
+	{                                            \
+		struct raft_fsm *fsm_ = &f->fsms[I]; \
+		int rv_;                             \
+		rv_ = fsm_->restore(fsm_, BUF);      \
+		munit_assert_int(rv_, ==, RV);       \
+		if (rv_ != 0) {                      \
+			raft_free((BUF)->base);      \
+		}                                    \
+	}
+
+/* Assert whether the table with the given name exists in the test database of
+ * the I'th node. */
+#define ASSERT_TABLE(I, NAME, EXISTS)                                        \
+	{                                                                    \
+		sqlite3_stmt *stmt_;                                         \
+		int rv_;                                                     \
+		SETUP_LEADER(I);                                             \
+		rv_ = sqlite3_prepare_v2(CONN(I), "SELECT * FROM " NAME, -1, \
+					 &stmt_, NULL);                      \
+		if (EXISTS) {                                                \
+			munit_assert_int(rv_, ==, SQLITE_OK);                \
+		} else {                                                     \
+			munit_assert_int(rv_, ==, SQLITE_ERROR);             \
+		}                                                            \
+		sqlite3_finalize(stmt_);                                     \
+		TEAR_DOWN_LEADER(I);                                         \
+	}
+
+/* Return the test logger data of the I'th node. */
+#define LOGGER(I) ((struct test_logger *)(CLUSTER_LOGGER(I))->data)
+
+/* Assert that the last message logged by the I'th node starts with PREFIX. */
+#define ASSERT_LOG_PREFIX(I, PREFIX)                                        \
+	{                                                                   \
+		const char *message_ = LOGGER(I)->message;                  \
+		munit_assert_int(strncmp(message_, PREFIX, strlen(PREFIX)), \
+				 ==, 0);                                    \
+	}
+
+/******************************************************************************
+ *
+ * fsm__snapshot and fsm__restore
+ *
+ ******************************************************************************/
+
+SUITE(fsm)
+
+/* A snapshot taken on one node can be restored on another one, replacing the
+ * content of its databases. */
+TEST(fsm, snapshotRestore, setUp, tearDown, 0, NULL)
+{
+	struct fixture *f = data;
+	struct raft_buffer buf;
+	CLUSTER_ELECT(0);
+	EXEC_SQL(0, "CREATE TABLE test (n INT)");
+	munit_assert_int(f->status, ==, SQLITE_DONE);
+	SNAPSHOT(0, &buf, 0);
+	EXEC_SQL(0, "CREATE TABLE test2 (n INT)");
+	ASSERT_TABLE(2, "test2", true);
+	RESTORE(2, &buf, 0);
+	ASSERT_TABLE(2, "test", true);
+	ASSERT_TABLE(2, "test2", false);
+	munit_assert_int(LOGGER(2)->level, !=, DQLITE_WARN);
+	return MUNIT_OK;
+}
+
+/* A snapshot created by a version that did not append the checksums trailer is
+ * still accepted, and a warning is logged. */
+TEST(fsm, restoreWithoutChecksums, setUp, tearDown, 0, NULL)
+{
+	struct fixture *f = data;
+	struct raft_buffer buf;
+	CLUSTER_ELECT(0);
+	EXEC_SQL(0, "CREATE TABLE test (n INT)");
+	SNAPSHOT(0, &buf, 3 /* Header plus one database */);
+	EXEC_SQL(0, "CREATE TABLE test2 (n INT)");
+	RESTORE(2, &buf, 0);
+	ASSERT_TABLE(2, "test", true);
+	ASSERT_TABLE(2, "test2", false);
+	munit_assert_int(LOGGER(2)->level, ==, DQLITE_WARN);
+	munit_assert_string_equal(
+	    LOGGER(2)->message, "snapshot has no checksums, skip verification");
+	return MUNIT_OK;
+}
+
+/* If the content of a database doesn't match its checksum, the snapshot is
+ * rejected. */
+TEST(fsm, restoreCorrupt, setUp, tearDown, 0, NULL)
+{
+	struct fixture *f = data;
+	struct raft_buffer buf;
+	uint8_t *byte;
+	CLUSTER_ELECT(0);
+	EXEC_SQL(0, "CREATE TABLE test (n INT)");
+	SNAPSHOT(0, &buf, 0);
+	byte = (uint8_t *)buf.base + buf.len / 2;
+	*byte = (uint8_t)~*byte;
+	RESTORE(2, &buf, RAFT_CORRUPT);
+	munit_assert_int(LOGGER(2)->level, ==, DQLITE_LOG_ERROR);
+	ASSERT_LOG_PREFIX(
+	    2, "snapshot database test.db: checksum mismatch: expected ");
+	munit_assert_ptr_not_null(strstr(LOGGER(2)->message, ", got "));
+	return MUNIT_OK;
+}
+
+/* If the header of a database doesn't match its checksum, the snapshot is
+ * rejected. */
+TEST(fsm, restoreCorruptFilename, setUp, tearDown, 0, NULL)
+{
+	struct fixture *f = data;
+	struct raft_buffer buf;
+	uint8_t *filename;
+	CLUSTER_ELECT(0);
+	EXEC_SQL(0, "CREATE TABLE test (n INT)");
+	SNAPSHOT(0, &buf, 0);
+	/* The filename of the first database follows the 16 bytes of the
+	 * snapshot header: turn "test.db" into "tesu.db". */
+	filename = (uint8_t *)buf.base + 16;
+	munit_assert_string_equal((const char *)filename, "test.db");
+	filename[3]++;
+	RESTORE(2, &buf, RAFT_CORRUPT);
+	munit_assert_int(LOGGER(2)->level, ==, DQLITE_LOG_ERROR);
+	ASSERT_LOG_PREFIX(
+	    2, "snapshot database tesu.db: checksum mismatch: expected ");
+	munit_assert_ptr_not_null(strstr(LOGGER(2)->message, ", got "));
+	return MUNIT_OK;
+}
+
+/* If the snapshot is truncated after the null byte of a database filename but
+ * before its padding, it's rejected without reading past the buffer. */
+TEST(fsm, restoreTruncatedFilename, setUp, tearDown, 0, NULL)
+{
+	struct fixture *f = data;
+	struct raft_buffer buf;
+	uint64_t *header;
+	buf.len = 16 + strlen("a.db") + 1;
+	buf.base = raft_malloc(buf.len);
+	munit_assert_ptr_not_null(buf.base);
+	header = buf.base;
+	header[0] = byte__flip64(1); /* Format */
+	header[1] = byte__flip64(1); /* Number of databases */
+	strcpy((char *)buf.base + 16, "a.db");
+	RESTORE(2, &buf, DQLITE_PARSE);
+	return MUNIT_OK;
+}
+
+/* If the snapshot header declares more databases than can be counted, it's
+ * rejected. */
+TEST(fsm, restoreTooManyDatabases, setUp, tearDown, 0, NULL)
+{
+	struct fixture *f = data;
+	struct raft_buffer buf;
+	uint64_t *header;
+	buf.len = 16;
+	buf.base = raft_malloc(buf.len);
+	munit_assert_ptr_not_null(buf.base);
+	header = buf.base;
+	header[0] = byte__flip64(1);                      /* Format */
+	header[1] = byte__flip64((uint64_t)UINT_MAX + 1); /* Databases */
+	RESTORE(2, &buf, RAFT_MALFORMED);
+	return MUNIT_OK;
+}
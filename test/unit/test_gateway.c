@@ -250,6 +250,27 @@ static void handleCb(struct handle *req, int status, int type)
 		FINALIZE(_stmt_id);             \
 	}
 
+/* Prepare and exec a statement, decoding its result into the fixture's
+ * response. */
+#define EXEC_RESULT(SQL)                        \
+	{                                       \
+		uint64_t _stmt_id;              \
+		struct request_prepare prepare; \
+		struct response_stmt stmt;      \
+		prepare.db_id = 0;              \
+		prepare.sql = SQL;              \
+		ENCODE(&prepare, prepare);      \
+		HANDLE(PREPARE);                \
+		ASSERT_CALLBACK(0, STMT);       \
+		DECODE(&stmt, stmt);            \
+		_stmt_id = stmt.id;             \
+		EXEC_SUBMIT(_stmt_id);          \
+		WAIT;                           \
+		ASSERT_CALLBACK(0, RESULT);     \
+		DECODE(&f->response, result);   \
+		FINALIZE(_stmt_id);             \
+	}
+
 /* Execute a pragma statement to lowers SQLite's page cache size, in order to
  * force it to write uncommitted dirty pages to the WAL and hance trigger calls
  * to the xFrames hook with non-commit batches. */
@@ -897,6 +918,68 @@ TEST_CASE(exec, restore, NULL)
 	return MUNIT_OK;
 }
 
+/* The last insert ID and number of rows affected of a statement executed in
+ * an implicit transaction are reported once the transaction is committed. */
+TEST_CASE(exec, result_implicit_transaction, NULL)
+{
+	struct exec_fixture *f = data;
+	(void)params;
+	CLUSTER_ELECT(0);
+	EXEC("CREATE TABLE test (n INT)");
+	EXEC("INSERT INTO test(n) VALUES(1)");
+	EXEC_RESULT("INSERT INTO test(n) VALUES(2), (3)");
+	munit_assert_int(f->response.last_insert_id, ==, 3);
+	munit_assert_int(f->response.rows_affected, ==, 2);
+	return MUNIT_OK;
+}
+
+/* Statements executed inside an explicit transaction report their own last
+ * insert ID and number of rows affected, and the COMMIT, whose result is
+ * filled only after its frames are applied, still reports the values of the
+ * last statement of the transaction. */
+TEST_CASE(exec, result_explicit_transaction, NULL)
+{
+	struct exec_fixture *f = data;
+	(void)params;
+	CLUSTER_ELECT(0);
+	EXEC("CREATE TABLE test (n INT)");
+	EXEC("BEGIN");
+	EXEC_RESULT("INSERT INTO test(n) VALUES(1), (2)");
+	munit_assert_int(f->response.last_insert_id, ==, 2);
+	munit_assert_int(f->response.rows_affected, ==, 2);
+	EXEC_RESULT("INSERT INTO test(n) VALUES(3)");
+	munit_assert_int(f->response.last_insert_id, ==, 3);
+	munit_assert_int(f->response.rows_affected, ==, 1);
+	EXEC_RESULT("COMMIT");
+	munit_assert_int(f->response.last_insert_id, ==, 3);
+	munit_assert_int(f->response.rows_affected, ==, 1);
+	return MUNIT_OK;
+}
+
+/* An upsert that takes the update path counts the updated row as affected, but
+ * doesn't change the last insert ID. */
+TEST_CASE(exec, result_upsert, NULL)
+{
+	struct exec_fixture *f = data;
+	(void)params;
+	CLUSTER_ELECT(0);
+	EXEC("CREATE TABLE test (id INTEGER PRIMARY KEY, n INT)");
+	EXEC_RESULT("INSERT INTO test(id, n) VALUES(1, 1), (2, 2)");
+	munit_assert_int(f->response.last_insert_id, ==, 2);
+	munit_assert_int(f->response.rows_affected, ==, 2);
+	EXEC_RESULT(
+	    "INSERT INTO test(id, n) VALUES(1, 10) "
+	    "ON CONFLICT(id) DO UPDATE SET n = excluded.n");
+	munit_assert_int(f->response.last_insert_id, ==, 2);
+	munit_assert_int(f->response.rows_affected, ==, 1);
+	EXEC_RESULT(
+	    "INSERT INTO test(id, n) VALUES(5, 5) "
+	    "ON CONFLICT(id) DO UPDATE SET n = excluded.n");
+	munit_assert_int(f->response.last_insert_id, ==, 5);
+	munit_assert_int(f->response.rows_affected, ==, 1);
+	return MUNIT_OK;
+}
+
 /******************************************************************************
  *
  * query